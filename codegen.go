@@ -0,0 +1,471 @@
+package main
+
+import (
+	"fmt"
+	"go/format"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// OpenAPISpec is the small slice of an OpenAPI 3 document that GenerateClient needs: the path
+// table and the named schemas under components.
+type OpenAPISpec struct {
+	Paths      map[string]map[string]OpenAPIOperation `yaml:"paths"`
+	Components struct {
+		Schemas map[string]OpenAPISchema `yaml:"schemas"`
+	} `yaml:"components"`
+}
+
+// OpenAPIOperation is one method entry under a path (e.g. paths./users.get).
+type OpenAPIOperation struct {
+	OperationID string                     `yaml:"operationId"`
+	Summary     string                     `yaml:"summary"`
+	Parameters  []OpenAPIParameter         `yaml:"parameters"`
+	RequestBody *OpenAPIRequestBody        `yaml:"requestBody"`
+	Responses   map[string]OpenAPIResponse `yaml:"responses"`
+}
+
+type OpenAPIParameter struct {
+	Name     string        `yaml:"name"`
+	In       string        `yaml:"in"`
+	Required bool          `yaml:"required"`
+	Schema   OpenAPISchema `yaml:"schema"`
+}
+
+type OpenAPIRequestBody struct {
+	Content map[string]OpenAPIMediaType `yaml:"content"`
+}
+
+type OpenAPIResponse struct {
+	Description string                      `yaml:"description"`
+	Content     map[string]OpenAPIMediaType `yaml:"content"`
+}
+
+type OpenAPIMediaType struct {
+	Schema OpenAPISchema `yaml:"schema"`
+}
+
+// OpenAPISchema is a (possibly $ref'd) JSON Schema node, trimmed to the fields the generator
+// understands.
+type OpenAPISchema struct {
+	Ref        string                   `yaml:"$ref"`
+	Type       string                   `yaml:"type"`
+	Format     string                   `yaml:"format"`
+	Nullable   bool                     `yaml:"nullable"`
+	Items      *OpenAPISchema           `yaml:"items"`
+	Properties map[string]OpenAPISchema `yaml:"properties"`
+	Required   []string                 `yaml:"required"`
+}
+
+// LoadOpenAPISpec reads and parses the OpenAPI 3 document at path.
+func LoadOpenAPISpec(path string) (*OpenAPISpec, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading spec: %w", err)
+	}
+	var spec OpenAPISpec
+	if err = yaml.Unmarshal(raw, &spec); err != nil {
+		return nil, fmt.Errorf("parsing spec: %w", err)
+	}
+	return &spec, nil
+}
+
+// GenerateClient emits a Go package under outDir with one function per OpenAPI operation and one
+// struct per named component schema, all built on a shared invoke() transport helper.
+func GenerateClient(spec *OpenAPISpec, outDir, packageName string) error {
+	if packageName == "" {
+		packageName = "client"
+	}
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		return fmt.Errorf("creating output directory: %w", err)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "package %s\n\n", packageName)
+	b.WriteString(clientRuntimeSource)
+
+	for _, name := range sortedKeysOf(spec.Components.Schemas) {
+		generateStruct(name, spec.Components.Schemas[name], spec, &b)
+	}
+
+	for _, path := range sortedPathKeys(spec.Paths) {
+		methods := spec.Paths[path]
+		for _, method := range sortedMethodKeys(methods) {
+			op := methods[method]
+			b.WriteString(generateOperation(path, method, op, spec, &b))
+		}
+	}
+
+	formatted, err := format.Source([]byte(b.String()))
+	if err != nil {
+		return fmt.Errorf("formatting generated source: %w", err)
+	}
+
+	return os.WriteFile(filepath.Join(outDir, "client.go"), formatted, 0644)
+}
+
+// clientRuntimeSource is the fixed part of every generated client: the Client type, its
+// constructor, and the invoke() helper every operation function calls through.
+const clientRuntimeSource = `import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Client is a typed wrapper around the API described by the OpenAPI spec this file was
+// generated from.
+type Client struct {
+	baseURL     string
+	bearerToken string
+	httpClient  *http.Client
+}
+
+// NewClient builds a Client, reusing the existing BaseUrl/BearerToken configuration convention.
+func NewClient(baseURL, bearerToken string) *Client {
+	return &Client{
+		baseURL:     baseURL,
+		bearerToken: bearerToken,
+		httpClient:  &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+// invoke sends a single request and decodes its JSON response into out, normalizing numbers the
+// same way the rest of this tool does so integer-valued fields don't come back as float64.
+func (c *Client) invoke(method, path string, query map[string]string, body interface{}, out interface{}) error {
+	u, err := url.Parse(c.baseURL + path)
+	if err != nil {
+		return fmt.Errorf("parsing URL: %w", err)
+	}
+	if len(query) > 0 {
+		q := u.Query()
+		for k, v := range query {
+			q.Set(k, v)
+		}
+		u.RawQuery = q.Encode()
+	}
+
+	var reqBody io.Reader
+	if body != nil {
+		data, mErr := json.Marshal(body)
+		if mErr != nil {
+			return fmt.Errorf("marshalling request body: %w", mErr)
+		}
+		reqBody = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, u.String(), reqBody)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.bearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.bearerToken)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("making request: %w", err)
+	}
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(resp.Body)
+
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("reading response body: %w", err)
+	}
+	if out == nil || len(raw) == 0 {
+		return nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var v interface{}
+	if err = dec.Decode(&v); err != nil {
+		return fmt.Errorf("decoding response: %w", err)
+	}
+
+	normalized, mErr := json.Marshal(normalizeResponseNumbers(v))
+	if mErr != nil {
+		return fmt.Errorf("normalizing response: %w", mErr)
+	}
+	return json.Unmarshal(normalized, out)
+}
+
+// normalizeResponseNumbers converts json.Number into int64 or float64 where possible, the same
+// rule apiCall itself applies to decoded responses.
+func normalizeResponseNumbers(v interface{}) interface{} {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(x))
+		for k, val := range x {
+			m[k] = normalizeResponseNumbers(val)
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(x))
+		for i, val := range x {
+			s[i] = normalizeResponseNumbers(val)
+		}
+		return s
+	case json.Number:
+		if i, err := x.Int64(); err == nil {
+			return i
+		}
+		if f, err := x.Float64(); err == nil {
+			return f
+		}
+		return x.String()
+	default:
+		return v
+	}
+}
+
+`
+
+// generateStruct writes the Go struct for schema, named exportedName(name), to out. Inline object
+// properties get their own nested struct, written to out ahead of this one, via fieldGoType.
+func generateStruct(name string, schema OpenAPISchema, spec *OpenAPISpec, out *strings.Builder) {
+	structName := exportedName(name)
+	var b strings.Builder
+	fmt.Fprintf(&b, "type %s struct {\n", structName)
+	for _, propName := range sortedKeysOf(schema.Properties) {
+		prop := schema.Properties[propName]
+		fieldType := fieldGoType(prop, spec, structName+exportedName(propName), out)
+		fmt.Fprintf(&b, "\t%s %s `json:\"%s\"`\n", exportedName(propName), fieldType, propName)
+	}
+	b.WriteString("}\n\n")
+	out.WriteString(b.String())
+}
+
+// fieldGoType maps a schema node to a Go type: integer->int64, number->float64, array->slice,
+// object->named struct or map, nullable scalars/refs -> pointer. An inline object schema (one
+// with no $ref) gets its own generated struct named exportedName(nameHint), written to out; a
+// $ref'd schema reuses the struct GenerateClient already emits for that component.
+func fieldGoType(schema OpenAPISchema, spec *OpenAPISpec, nameHint string, out *strings.Builder) string {
+	if schema.Ref != "" {
+		t := exportedName(refName(schema.Ref))
+		if schema.Nullable {
+			return "*" + t
+		}
+		return t
+	}
+
+	switch schema.Type {
+	case "integer":
+		if schema.Nullable {
+			return "*int64"
+		}
+		return "int64"
+	case "number":
+		if schema.Nullable {
+			return "*float64"
+		}
+		return "float64"
+	case "boolean":
+		if schema.Nullable {
+			return "*bool"
+		}
+		return "bool"
+	case "string":
+		if schema.Nullable {
+			return "*string"
+		}
+		return "string"
+	case "array":
+		if schema.Items == nil {
+			return "[]interface{}"
+		}
+		return "[]" + fieldGoType(*schema.Items, spec, nameHint+"Item", out)
+	case "object":
+		if len(schema.Properties) == 0 {
+			return "map[string]interface{}"
+		}
+		generateStruct(nameHint, schema, spec, out)
+		t := exportedName(nameHint)
+		if schema.Nullable {
+			return "*" + t
+		}
+		return t
+	default:
+		return "interface{}"
+	}
+}
+
+var refNamePattern = regexp.MustCompile(`[^/]+$`)
+
+func refName(ref string) string {
+	return refNamePattern.FindString(ref)
+}
+
+var nonAlnum = regexp.MustCompile(`[^a-zA-Z0-9]+`)
+
+// exportedName turns a snake_case/kebab-case/camelCase identifier into an exported Go name.
+func exportedName(name string) string {
+	parts := nonAlnum.Split(name, -1)
+	var b strings.Builder
+	for _, part := range parts {
+		if part == "" {
+			continue
+		}
+		b.WriteString(strings.ToUpper(part[:1]))
+		b.WriteString(part[1:])
+	}
+	if b.Len() == 0 {
+		return "Field"
+	}
+	return b.String()
+}
+
+var pathParamPattern = regexp.MustCompile(`\{([^}]+)}`)
+
+// generateOperation emits a single Client method for one path+method pair to out, returning the
+// method's own source (the caller appends it after any nested structs generateOperation wrote to
+// out for inline body/response object schemas).
+func generateOperation(path, method string, op OpenAPIOperation, spec *OpenAPISpec, out *strings.Builder) string {
+	funcName := op.OperationID
+	if funcName == "" {
+		funcName = method + "_" + path
+	}
+	funcName = exportedName(funcName)
+
+	pathParams := pathParamPattern.FindAllStringSubmatch(path, -1)
+	queryParams := make([]OpenAPIParameter, 0, len(op.Parameters))
+	for _, p := range op.Parameters {
+		if p.In == "query" {
+			queryParams = append(queryParams, p)
+		}
+	}
+
+	bodyType := ""
+	if op.RequestBody != nil {
+		if mt, ok := op.RequestBody.Content["application/json"]; ok {
+			bodyType = fieldGoType(mt.Schema, spec, funcName+"Body", out)
+		}
+	}
+
+	responseType := ""
+	for _, status := range []string{"200", "201", "default"} {
+		if resp, ok := op.Responses[status]; ok {
+			if mt, ok := resp.Content["application/json"]; ok {
+				responseType = fieldGoType(mt.Schema, spec, funcName+"Response", out)
+				break
+			}
+		}
+	}
+
+	var sig strings.Builder
+	fmt.Fprintf(&sig, "func (c *Client) %s(", funcName)
+	args := make([]string, 0, len(pathParams)+2)
+	for _, m := range pathParams {
+		args = append(args, goArgName(m[1])+" string")
+	}
+	if len(queryParams) > 0 {
+		args = append(args, "query map[string]string")
+	}
+	if bodyType != "" {
+		args = append(args, "body "+bodyType)
+	}
+	sig.WriteString(strings.Join(args, ", "))
+	if responseType != "" {
+		fmt.Fprintf(&sig, ") (*%s, error) {\n", responseType)
+	} else {
+		sig.WriteString(") error {\n")
+	}
+
+	var b strings.Builder
+	if op.Summary != "" {
+		fmt.Fprintf(&b, "// %s %s\n", funcName, op.Summary)
+	}
+	b.WriteString(sig.String())
+
+	fmt.Fprintf(&b, "\tpath := %s\n", pathExpr(path, pathParams))
+
+	queryArg := "nil"
+	if len(queryParams) > 0 {
+		queryArg = "query"
+	}
+	bodyArg := "nil"
+	if bodyType != "" {
+		bodyArg = "body"
+	}
+
+	if responseType != "" {
+		b.WriteString("\tvar out " + responseType + "\n")
+		fmt.Fprintf(&b, "\tif err := c.invoke(%q, path, %s, %s, &out); err != nil {\n\t\treturn nil, err\n\t}\n", strings.ToUpper(method), queryArg, bodyArg)
+		b.WriteString("\treturn &out, nil\n")
+	} else {
+		fmt.Fprintf(&b, "\treturn c.invoke(%q, path, %s, %s, nil)\n", strings.ToUpper(method), queryArg, bodyArg)
+	}
+	b.WriteString("}\n\n")
+
+	return b.String()
+}
+
+// pathExpr turns an OpenAPI path template like "/users/{id}" into a Go expression that
+// concatenates the literal segments with url.PathEscape calls for each path parameter.
+func pathExpr(path string, pathParams [][]string) string {
+	if len(pathParams) == 0 {
+		return fmt.Sprintf("%q", path)
+	}
+
+	var parts []string
+	rest := path
+	for _, m := range pathParams {
+		idx := strings.Index(rest, m[0])
+		literal := rest[:idx]
+		if literal != "" {
+			parts = append(parts, fmt.Sprintf("%q", literal))
+		}
+		parts = append(parts, "url.PathEscape("+goArgName(m[1])+")")
+		rest = rest[idx+len(m[0]):]
+	}
+	if rest != "" {
+		parts = append(parts, fmt.Sprintf("%q", rest))
+	}
+	return strings.Join(parts, " + ")
+}
+
+func goArgName(pathParam string) string {
+	name := exportedName(pathParam)
+	if name == "" {
+		return "arg"
+	}
+	return strings.ToLower(name[:1]) + name[1:]
+}
+
+func sortedKeysOf(m map[string]OpenAPISchema) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedPathKeys(m map[string]map[string]OpenAPIOperation) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedMethodKeys(m map[string]OpenAPIOperation) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"fmt"
+	"io"
+)
+
+// decompressBody wraps body in the reader matching contentEncoding ("gzip" or "deflate"),
+// returning body unchanged for any other value (including "" and "identity").
+func decompressBody(contentEncoding string, body io.ReadCloser) (io.ReadCloser, error) {
+	switch contentEncoding {
+	case "gzip":
+		return gzip.NewReader(body)
+	case "deflate":
+		return flate.NewReader(body), nil
+	default:
+		return body, nil
+	}
+}
+
+// resolveCompressLevel returns the gzip level to compress with: level itself when the config
+// set compress_level explicitly, or gzip.DefaultCompression when it didn't. A plain int can't
+// tell "unset" apart from an explicit compress_level: 0 (store-only), since 0 is also an int's
+// zero value, so the config field is a pointer instead.
+func resolveCompressLevel(level *int) int {
+	if level == nil {
+		return gzip.DefaultCompression
+	}
+	return *level
+}
+
+// compressBody gzips data at the given compression level, for use as a request body when
+// compression is enabled. level is passed straight to gzip.NewWriterLevel, so
+// gzip.DefaultCompression (-1) picks the zlib default and gzip.NoCompression (0) stores the
+// data uncompressed.
+func compressBody(data []byte, level int) ([]byte, error) {
+	var buf bytes.Buffer
+	writer, err := gzip.NewWriterLevel(&buf, level)
+	if err != nil {
+		return nil, fmt.Errorf("creating gzip writer: %w", err)
+	}
+	if _, err = writer.Write(data); err != nil {
+		return nil, fmt.Errorf("writing compressed body: %w", err)
+	}
+	if err = writer.Close(); err != nil {
+		return nil, fmt.Errorf("closing gzip writer: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// compressRequestBody gzips data when a.compressRequest is enabled and returns the body bytes to
+// send along with the Content-Encoding header value to set ("" when compression is off).
+func (a *Api) compressRequestBody(data []byte) ([]byte, string, error) {
+	if !a.compressRequest || len(data) == 0 {
+		return data, "", nil
+	}
+	compressed, err := compressBody(data, a.compressLevel)
+	if err != nil {
+		return nil, "", err
+	}
+	return compressed, "gzip", nil
+}
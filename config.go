@@ -7,10 +7,12 @@ import (
 )
 
 type Config struct {
-	BaseUrl     string `yaml:"base_url" env-default:"https://test.site/"`
-	InputPath   string `yaml:"input_path" env-default:""`
-	OutputPath  string `yaml:"output_path" env-default:""`
-	BearerToken string `yaml:"bearer_token" env-default:""`
+	BaseUrl         string `yaml:"base_url" env-default:"https://test.site/"`
+	InputPath       string `yaml:"input_path" env-default:""`
+	OutputPath      string `yaml:"output_path" env-default:""`
+	BearerToken     string `yaml:"bearer_token" env-default:""`
+	CompressRequest string `yaml:"compress_request" env-default:""`
+	CompressLevel   *int   `yaml:"compress_level"`
 }
 
 var instance *Config
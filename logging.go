@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	logRotateSize    = 10 * 1024 * 1024 // 10MB
+	logRotateBackups = 5
+	logRotateMaxAge  = 24 * time.Hour
+)
+
+// rotatingFile is an io.WriteCloser over errors.log that rotates to errors.log.1..N once the
+// current file exceeds logRotateSize, or once logRotateMaxAge has passed since it was last
+// rotated, whichever comes first, keeping at most logRotateBackups old files. This lets a
+// long-running scheduled invocation log indefinitely without filling the disk, even one whose
+// traffic is too low to ever cross the size threshold on its own.
+type rotatingFile struct {
+	mu        sync.Mutex
+	path      string
+	file      *os.File
+	size      int64
+	rotatedAt time.Time
+}
+
+func newRotatingFile(path string) (*rotatingFile, error) {
+	_ = os.Remove(path)
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &rotatingFile{path: path, file: file, rotatedAt: time.Now()}, nil
+}
+
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	sizeExceeded := r.size > 0 && r.size+int64(len(p)) > logRotateSize
+	aged := r.size > 0 && time.Since(r.rotatedAt) > logRotateMaxAge
+	if sizeExceeded || aged {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+func (r *rotatingFile) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return err
+	}
+
+	for i := logRotateBackups - 1; i >= 1; i-- {
+		src := fmt.Sprintf("%s.%d", r.path, i)
+		dst := fmt.Sprintf("%s.%d", r.path, i+1)
+		if _, err := os.Stat(src); err == nil {
+			_ = os.Rename(src, dst)
+		}
+	}
+	_ = os.Rename(r.path, r.path+".1")
+
+	file, err := os.OpenFile(r.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	r.file = file
+	r.size = 0
+	r.rotatedAt = time.Now()
+	return nil
+}
+
+func (r *rotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.file.Close()
+}
+
+// newLogger builds the structured logger for the whole run. logPath is rotated by rotatingFile;
+// format selects slog's text or JSON handler, and level filters by the usual debug/info/warn/error
+// names.
+func newLogger(logPath, format, level string) (*slog.Logger, io.Closer, error) {
+	rf, err := newRotatingFile(logPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("opening log file: %w", err)
+	}
+
+	opts := &slog.HandlerOptions{Level: parseLogLevel(level)}
+
+	var handler slog.Handler
+	if strings.EqualFold(format, "json") {
+		handler = slog.NewJSONHandler(rf, opts)
+	} else {
+		handler = slog.NewTextHandler(rf, opts)
+	}
+
+	return slog.New(handler), rf, nil
+}
+
+func parseLogLevel(level string) slog.Level {
+	switch strings.ToLower(level) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
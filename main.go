@@ -7,9 +7,9 @@ import (
 	"flag"
 	"fmt"
 	"io"
+	"log/slog"
 	"mime/multipart"
 	"net/http"
-	"net/url"
 	"os"
 	"strings"
 	"time"
@@ -39,11 +39,19 @@ type PageData struct {
 }
 
 type Api struct {
-	url        string
-	inputPath  string
-	outputPath string
-	token      string
-	debug      bool
+	url             string
+	inputPath       string
+	outputPath      string
+	token           string
+	debug           bool
+	concurrency     int
+	format          string
+	columns         []string
+	compressRequest bool
+	compressLevel   int
+	client          *http.Client
+	consoleOut      io.Writer
+	logger          *slog.Logger
 }
 
 func main() {
@@ -55,10 +63,36 @@ func main() {
 	apiMethod := flag.String("method", "GET", "HTTP method (GET, POST, etc.)")
 	workPath := flag.String("path", "", "working directory")
 	boundary := flag.String("boundary", "", "File name to be send using boundary")
+	playbook := flag.String("playbook", "", "path to a YAML playbook file; runs a scenario instead of a single call")
+	concurrency := flag.Int("concurrency", defaultConcurrency, "number of pages to fetch in parallel")
+	format := flag.String("format", FormatCSVWindows1251, "output format: csv-win1251, csv-utf8, json, ndjson, stdout (parquet is recognised but not available in this build)")
+	columns := flag.String("columns", "", "comma-separated column order; defaults to the sorted keys of the first row")
+	genClient := flag.String("gen-client", "", "path to an OpenAPI 3 spec; generates a typed Go client instead of making a call")
+	genOut := flag.String("out", "", "output directory for --gen-client")
+	logFormat := flag.String("log-format", "text", "log output format: text or json")
+	logLevel := flag.String("log-level", "info", "log level: debug, info, warn, error")
 	debug := flag.Bool("debug", false, "enable debug mode")
 	flag.Parse()
 
-	if *apiURL == "" {
+	if *genClient != "" {
+		if *genOut == "" {
+			fmt.Println("Please provide an output directory with -out.")
+			os.Exit(1)
+		}
+		spec, genErr := LoadOpenAPISpec(*genClient)
+		if genErr != nil {
+			fmt.Println("#Error: loading spec:", genErr)
+			os.Exit(1)
+		}
+		if genErr = GenerateClient(spec, *genOut, ""); genErr != nil {
+			fmt.Println("#Error: generating client:", genErr)
+			os.Exit(1)
+		}
+		fmt.Printf("generated client package in %s\n", *genOut)
+		return
+	}
+
+	if *apiURL == "" && *playbook == "" {
 		fmt.Println("Please provide an API URL.")
 		return
 	}
@@ -75,11 +109,23 @@ func main() {
 		return
 	}
 
+	var columnList []string
+	if *columns != "" {
+		columnList = strings.Split(*columns, ",")
+	}
+
 	api := Api{
-		url:        fmt.Sprintf("%s%s", baseUrl, *apiURL),
-		inputPath:  conf.InputPath,
-		outputPath: conf.OutputPath,
-		token:      conf.BearerToken,
+		url:             fmt.Sprintf("%s%s", baseUrl, *apiURL),
+		inputPath:       conf.InputPath,
+		outputPath:      conf.OutputPath,
+		token:           conf.BearerToken,
+		concurrency:     *concurrency,
+		format:          *format,
+		columns:         columnList,
+		compressRequest: strings.EqualFold(conf.CompressRequest, "gzip"),
+		compressLevel:   resolveCompressLevel(conf.CompressLevel),
+		client:          newHttpClient(),
+		consoleOut:      os.Stdout,
 	}
 	if workPath != nil && *workPath != "" {
 		api.inputPath = *workPath
@@ -95,22 +141,31 @@ func main() {
 		api.debug = true
 	}
 
-	logFile := fmt.Sprintf("%serrors.log", api.outputPath)
-	_ = os.Remove(logFile)
-	file, err := os.OpenFile(logFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	logPath := fmt.Sprintf("%serrors.log", api.outputPath)
+	logger, logCloser, err := newLogger(logPath, *logFormat, *logLevel)
 	if err != nil {
-		fmt.Printf("opening or creating log file: %v\n", err)
+		fmt.Println("#Error:", err)
 		return
 	}
-	defer func(file *os.File) {
+	api.logger = logger
+	defer func() {
 		fmt.Printf("Finished in %s\n", time.Since(now))
-		err = file.Close()
-		if err != nil {
-			fmt.Println("closing log file:", err)
-			return
+		_ = logCloser.Close()
+	}()
+
+	if *playbook != "" {
+		perr := api.RunPlaybook(*playbook)
+		if perr != nil {
+			api.logger.Error("playbook failed", "error", perr)
+			fmt.Println("#Error: playbook:", perr)
 		}
-	}(file)
-	os.Stdout = file
+		fmt.Printf("Finished in %s\n", time.Since(now))
+		_ = logCloser.Close()
+		if perr != nil {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
 
 	api.removeFiles()
 
@@ -123,8 +178,9 @@ func main() {
 
 	var jsonBytes []byte
 	if method != "GET" {
-		jsonBytes, err = prepareBody(api.inputPath)
+		jsonBytes, err = prepareBody(api.inputPath, api.logger)
 		if err != nil {
+			api.logger.Error("preparing body", "error", err)
 			fmt.Println("#Error: preparing body:", err)
 			return
 		}
@@ -134,153 +190,33 @@ func main() {
 
 }
 
+// doHttpMethod fetches output, probing the first page and then fetching any remaining pages
+// through a bounded worker pool, streaming rows into the configured OutputWriter as each page's
+// turn in page order comes up (see fetchAndWritePages).
 func (a *Api) doHttpMethod(method string, data []byte, output string) {
-	fmt.Printf("%s: %s\n", method, a.url)
+	a.logger.Info("request start", "method", method, "url", a.url)
 
-	req, err := http.NewRequest(method, a.url, bytes.NewBuffer(data))
-	if err != nil {
-		fmt.Println("#Error: creating request:", err)
-		return
-	}
-	req.Header.Set("Content-Type", "application/json")
-	if a.token != "" {
-		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", a.token))
+	total, dest, missing, err := a.fetchAndWritePages(method, data, output)
+	if len(missing) > 0 {
+		a.logger.Warn("some pages could not be fetched", "missing_pages", missing)
+		fmt.Printf("#Warn: %d pages missing, wrote the rest\n", len(missing))
 	}
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
 	if err != nil {
-		fmt.Println("#Error: making request:", err)
-		return
-	}
-
-	defer func(Body io.ReadCloser) {
-		err = Body.Close()
-		if err != nil {
-			fmt.Println("#Error: closing response body:", err)
+		a.logger.Error("request failed", "method", method, "url", a.url, "error", err)
+		fmt.Println("#Error:", err)
+		if total == 0 {
 			return
 		}
-	}(resp.Body)
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		fmt.Println("#Error: reading response body:", err)
-		return
-	}
-
-	if a.debug {
-		fmt.Println("Response ===================================== >>>")
-		fmt.Printf("%s\n", string(body))
-		fmt.Println("Response ===================================== <<<")
-	}
-
-	var apiResponse ApiResponse
-	//err = json.Unmarshal(body, &apiResponse)
-	dec := json.NewDecoder(bytes.NewReader(body))
-	dec.UseNumber()
-	err = dec.Decode(&apiResponse)
-	if err != nil {
-		fmt.Println("#Error: parsing JSON:", err)
-		return
 	}
-
-	if !apiResponse.Success {
-		if apiResponse.Message != "" {
-			fmt.Println("#Error: ", apiResponse.Message)
-		}
-		//if len(apiResponse.Errors) > 0 {
-		//	fmt.Println("#Error: ", apiResponse.Errors)
-		//}
-		return
-	}
-
-	a.saveResponse(apiResponse, output)
-
-	if apiResponse.Meta.Total > apiResponse.Meta.Page {
-		nextPage := apiResponse.Meta.Page + 1
-		fmt.Printf("fetching page %d of %d...\n", nextPage, apiResponse.Meta.Total)
-
-		parsedParams, err := url.Parse(a.url)
-		if err != nil {
-			fmt.Println("#Error: parsing URL:", err)
-			return
-		}
-		params := parsedParams.Query()
-		params.Set("page", fmt.Sprintf("%d", nextPage))
-		parsedParams.RawQuery = params.Encode()
-		a.url = parsedParams.String()
-
-		a.doHttpMethod("GET", nil, fmt.Sprintf("output_%d.csv", nextPage))
+	if total > 0 {
+		a.logger.Info("records written", "count", total, "destination", dest)
+		fmt.Printf("received %d records: %s\n", total, dest)
 	}
 }
 
-func (a *Api) saveResponse(response ApiResponse, output string) {
-	if !response.Success {
-		fmt.Println("#Error: call was not successful")
-		return
-	}
-
-	// Create CSV file
-	csvFile, err := os.Create(fmt.Sprintf("%s%s", a.outputPath, output))
-	if err != nil {
-		fmt.Println("#Error: creating file:", err)
-		return
-	}
-	defer func(csvFile *os.File) {
-		err = csvFile.Close()
-		if err != nil {
-			fmt.Println("#Error: closing file:", err)
-			return
-		}
-	}(csvFile)
-
-	writer := csv.NewWriter(csvFile)
+func prepareBody(path string, logger *slog.Logger) ([]byte, error) {
 
-	// Write header
-	if len(response.Data) == 0 {
-		fmt.Println("#Warn: no data to write")
-		return
-	}
-
-	// Write header
-	var header []string
-	for key := range response.Data[0] {
-		header = append(header, key)
-	}
-	err = writer.Write(header)
-	if err != nil {
-		fmt.Println("#Error: writing header:", err)
-		return
-	}
-
-	// Write data rows
-	for _, row := range response.Data {
-		var record []string
-		for _, key := range header {
-			value := fmt.Sprintf("%v", row[key])
-			value = strings.ReplaceAll(value, "\n", " ")
-			value = strings.ReplaceAll(value, "\r", "")
-			encoded, e := ConvertToWindows1251(value)
-			if a.debug && e != nil {
-				fmt.Printf("#Error: converting string: %s\n", e)
-				fmt.Printf("#Error: failed to convert: %s\n", value)
-			}
-			record = append(record, encoded)
-		}
-		err = writer.Write(record)
-		if err != nil {
-			fmt.Println("#Error: writing record:", err)
-			return
-		}
-	}
-
-	writer.Flush()
-	fmt.Printf("received %d records: %s\n", len(response.Data), output)
-}
-
-func prepareBody(path string) ([]byte, error) {
-
-	singleFile, err := readFileContent(path, objectFile)
+	singleFile, err := readFileContent(path, objectFile, logger)
 	if err == nil {
 		if len(singleFile) > 0 {
 			obj := singleFile[0]
@@ -289,7 +225,7 @@ func prepareBody(path string) ([]byte, error) {
 		return nil, fmt.Errorf("empty object data file")
 	}
 
-	singleFile, err = readFileContent(path, inputFile)
+	singleFile, err = readFileContent(path, inputFile, logger)
 	if err == nil {
 		return getJsonBytes(singleFile)
 	}
@@ -304,7 +240,7 @@ func prepareBody(path string) ([]byte, error) {
 	for _, file := range files {
 		if strings.HasPrefix(file.Name(), "input_") && strings.HasSuffix(file.Name(), ".csv") {
 
-			jsonPayload, err := readFileContent(path, file.Name())
+			jsonPayload, err := readFileContent(path, file.Name(), logger)
 			if err != nil {
 				return nil, fmt.Errorf("reading file content: %s: %w", file.Name(), err)
 			}
@@ -319,7 +255,7 @@ func prepareBody(path string) ([]byte, error) {
 	return getJsonBytes(result)
 }
 
-func readFileContent(path, fileName string) ([]map[string]interface{}, error) {
+func readFileContent(path, fileName string, logger *slog.Logger) ([]map[string]interface{}, error) {
 	file, err := os.Open(fmt.Sprintf("%s%s", path, fileName))
 	if err != nil {
 		return nil, fmt.Errorf("opening file: %s: %s", fileName, err)
@@ -327,12 +263,11 @@ func readFileContent(path, fileName string) ([]map[string]interface{}, error) {
 	defer func(file *os.File) {
 		err := file.Close()
 		if err != nil {
-			fmt.Println("#Error: closing file:", err)
-			return
+			logger.Error("closing file", "file", fileName, "error", err)
 		}
 	}(file)
 
-	fmt.Println("Reading file:", fileName)
+	logger.Info("reading file", "file", fileName)
 
 	reader := csv.NewReader(file)
 	records, err := reader.ReadAll()
@@ -347,7 +282,7 @@ func readFileContent(path, fileName string) ([]map[string]interface{}, error) {
 		for i, key := range header {
 			field, err := ConvertToUTF8(row[i])
 			if err != nil {
-				fmt.Println("#Error: converting to utf-8:", err)
+				logger.Error("converting to utf-8", "error", err)
 			}
 			record[key] = field
 		}
@@ -396,7 +331,7 @@ func ConvertToWindows1251(utf8Str string) (string, error) {
 func (a *Api) removeFiles() {
 	files, err := os.ReadDir(a.outputPath)
 	if err != nil {
-		fmt.Println("reading directory:", err)
+		a.logger.Error("reading directory", "path", a.outputPath, "error", err)
 		return
 	}
 
@@ -405,7 +340,7 @@ func (a *Api) removeFiles() {
 			if strings.HasPrefix(file.Name(), "output") && strings.HasSuffix(file.Name(), ".csv") {
 				err := os.Remove(fmt.Sprintf("%s%s", a.outputPath, file.Name()))
 				if err != nil {
-					fmt.Printf("deleting file %s: %v\n", file.Name(), err)
+					a.logger.Error("deleting file", "file", file.Name(), "error", err)
 				}
 			}
 		}
@@ -413,17 +348,18 @@ func (a *Api) removeFiles() {
 }
 
 func (a *Api) doMultipartPost(boundary string) {
-	fmt.Printf("POST: %s\n", a.url)
+	a.logger.Info("request start", "method", "POST", "url", a.url)
+	start := time.Now()
 
 	file, err := os.Open(fmt.Sprintf("%s%s", a.inputPath, boundary))
 	if err != nil {
-		fmt.Println("#Error: opening file:", err)
+		a.logger.Error("opening file", "file", boundary, "error", err)
 		return
 	}
 	defer func(file *os.File) {
 		err = file.Close()
 		if err != nil {
-			fmt.Println("#Error: closing file:", err)
+			a.logger.Error("closing file", "file", boundary, "error", err)
 			return
 		}
 	}(file)
@@ -432,52 +368,63 @@ func (a *Api) doMultipartPost(boundary string) {
 	writer := multipart.NewWriter(body)
 	part, err := writer.CreateFormFile("file", boundary)
 	if err != nil {
-		fmt.Println("#Error: creating form file:", err)
+		a.logger.Error("creating form file", "error", err)
 		return
 	}
 
 	_, err = io.Copy(part, file)
 	if err != nil {
-		fmt.Println("#Error: copying file to form file:", err)
+		a.logger.Error("copying file to form file", "error", err)
 		return
 	}
 
 	err = writer.Close()
 	if err != nil {
-		fmt.Println("#Error: closing writer:", err)
+		a.logger.Error("closing writer", "error", err)
 		return
 	}
 
-	fmt.Println("Body ===================================== >>>")
-	fmt.Printf("%s\n", body)
-	fmt.Println("Body ===================================== <<<")
+	if a.debug {
+		fmt.Println("Body ===================================== >>>")
+		fmt.Printf("%s\n", body)
+		fmt.Println("Body ===================================== <<<")
+	}
 
-	req, err := http.NewRequest("POST", a.url, body)
+	requestBody, contentEncoding, err := a.compressRequestBody(body.Bytes())
 	if err != nil {
-		fmt.Println("#Error: creating request:", err)
+		a.logger.Error("compressing request body", "error", err)
+		return
+	}
+
+	req, err := http.NewRequest("POST", a.url, bytes.NewReader(requestBody))
+	if err != nil {
+		a.logger.Error("creating request", "error", err)
 		return
 	}
 	content := writer.FormDataContentType()
-	fmt.Println("Content-Type:", content)
 	req.Header.Set("Content-Type", content)
+	req.Header.Set("Accept-Encoding", "gzip, deflate")
+	if contentEncoding != "" {
+		req.Header.Set("Content-Encoding", contentEncoding)
+	}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := a.client.Do(req)
 	if err != nil {
-		fmt.Println("#Error: making request:", err)
+		a.logger.Error("making request", "error", err)
 		return
 	}
 
 	defer func(Body io.ReadCloser) {
 		err := Body.Close()
 		if err != nil {
-			fmt.Println("#Error: closing response body:", err)
+			a.logger.Error("closing response body", "error", err)
 			return
 		}
 	}(resp.Body)
 
+	a.logger.Info("response received", "method", "POST", "url", a.url, "status", resp.StatusCode, "duration_ms", time.Since(start).Milliseconds())
 	if resp.StatusCode > 299 {
-		fmt.Printf("#Error: response status %s\n", resp.Status)
+		a.logger.Error("response status", "status", resp.Status)
 	}
 
 	//response, err := io.ReadAll(resp.Body)
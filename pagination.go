@@ -0,0 +1,320 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	defaultConcurrency = 4
+	maxRetries         = 3
+)
+
+// newHttpClient returns the shared, connection-pooling client used for every request the tool
+// makes, so repeated page fetches reuse TCP connections instead of opening a fresh one each time.
+func newHttpClient() *http.Client {
+	return &http.Client{
+		Timeout: 60 * time.Second,
+		Transport: &http.Transport{
+			MaxIdleConns:        100,
+			MaxIdleConnsPerHost: 20,
+			IdleConnTimeout:     90 * time.Second,
+		},
+	}
+}
+
+// fetchAndWritePages probes page 1 to learn the total page count, then fetches the remaining
+// pages through a bounded worker pool sized by a.concurrency and streams each page's rows into
+// the configured OutputWriter as soon as its turn in page order comes up. Unlike buffering every
+// decoded page before writing a single row, a page is only ever held in memory until the page(s)
+// before it have been written. It returns the number of rows written, the destination path, and
+// the 1-based page numbers that could not be fetched after retries were exhausted.
+func (a *Api) fetchAndWritePages(method string, data []byte, output string) (int, string, []int, error) {
+	first, err := a.fetchPage(method, data, 1)
+	if err != nil {
+		return 0, "", nil, err
+	}
+	if !first.Success {
+		if first.Message != "" {
+			return 0, "", nil, fmt.Errorf("%s", first.Message)
+		}
+		return 0, "", nil, fmt.Errorf("call was not successful")
+	}
+
+	header := resolveColumns(a.columns, first.Data)
+	if header == nil {
+		a.logger.Warn("no data to write")
+		return 0, "", nil, nil
+	}
+
+	dest := outputDestination(a.outputPath, output, a.format)
+	writer, err := NewOutputWriter(a.format, dest, a.debug, a.consoleOut, a.logger)
+	if err != nil {
+		return 0, dest, nil, fmt.Errorf("opening output writer: %w", err)
+	}
+	defer func() {
+		if cErr := writer.Close(); cErr != nil {
+			a.logger.Error("closing output", "error", cErr)
+		}
+	}()
+
+	if err = writer.WriteHeader(header); err != nil {
+		return 0, dest, nil, fmt.Errorf("writing header: %w", err)
+	}
+
+	total := first.Meta.Total
+	if total < 1 {
+		total = 1
+	}
+
+	written, err := writeRows(writer, first)
+	if err != nil {
+		return written, dest, nil, fmt.Errorf("writing record: %w", err)
+	}
+	if total == 1 {
+		return written, dest, nil, nil
+	}
+
+	concurrency := a.concurrency
+	if concurrency < 1 {
+		concurrency = defaultConcurrency
+	}
+
+	type pageResult struct {
+		page int
+		resp *ApiResponse
+		err  error
+	}
+
+	sem := make(chan struct{}, concurrency)
+	results := make(chan pageResult, total-1)
+	var wg sync.WaitGroup
+
+	// Dispatch runs in its own goroutine so the loop below can write pages while later ones are
+	// still being fetched.
+	go func() {
+		for page := 2; page <= total; page++ {
+			page := page
+			wg.Add(1)
+			sem <- struct{}{}
+			go func() {
+				defer wg.Done()
+				defer func() { <-sem }()
+
+				a.logger.Info("fetching page", "page", page, "total", total)
+				resp, fErr := a.fetchPage(method, data, page)
+				results <- pageResult{page: page, resp: resp, err: fErr}
+			}()
+		}
+		wg.Wait()
+		close(results)
+	}()
+
+	// Results arrive in whatever order their workers finish, so pages that complete ahead of
+	// their turn wait in pending until fetchAndWritePages has written every page before them.
+	pending := make(map[int]*ApiResponse)
+	failed := make(map[int]bool)
+	next := 2
+	var writeErr error
+
+	flush := func() {
+		for next <= total {
+			if r, ok := pending[next]; ok {
+				delete(pending, next)
+				if writeErr == nil {
+					n, wErr := writeRows(writer, r)
+					written += n
+					if wErr != nil {
+						writeErr = fmt.Errorf("writing record: %w", wErr)
+					}
+				}
+				next++
+				continue
+			}
+			if failed[next] {
+				next++
+				continue
+			}
+			break
+		}
+	}
+
+	for res := range results {
+		if res.err != nil {
+			failed[res.page] = true
+		} else {
+			pending[res.page] = res.resp
+		}
+		flush()
+	}
+
+	var missing []int
+	for page := range failed {
+		missing = append(missing, page)
+	}
+	sort.Ints(missing)
+
+	if writeErr != nil {
+		return written, dest, missing, writeErr
+	}
+	if len(missing) > 0 {
+		return written, dest, missing, fmt.Errorf("%d of %d pages failed", len(missing), total-1)
+	}
+
+	return written, dest, nil, nil
+}
+
+// writeRows writes every row of r through writer and returns how many rows it wrote before
+// stopping at the first error, if any.
+func writeRows(writer OutputWriter, r *ApiResponse) (int, error) {
+	n := 0
+	for _, row := range r.Data {
+		if err := writer.WriteRow(row); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// fetchPage requests a single page of a.url, retrying on transient failures, and decodes the
+// JSON body into an ApiResponse.
+func (a *Api) fetchPage(method string, data []byte, page int) (*ApiResponse, error) {
+	pageURL, err := withPageParam(a.url, page)
+	if err != nil {
+		return nil, fmt.Errorf("parsing URL: %w", err)
+	}
+
+	requestBody, contentEncoding, err := a.compressRequestBody(data)
+	if err != nil {
+		return nil, fmt.Errorf("compressing request body: %w", err)
+	}
+
+	start := time.Now()
+	resp, err := a.doRequestWithRetry(func() (*http.Request, error) {
+		req, rErr := http.NewRequest(method, pageURL, bytes.NewReader(requestBody))
+		if rErr != nil {
+			return nil, rErr
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept-Encoding", "gzip, deflate")
+		if contentEncoding != "" {
+			req.Header.Set("Content-Encoding", contentEncoding)
+		}
+		if a.token != "" {
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", a.token))
+		}
+		return req, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("fetching page %d: %w", page, err)
+	}
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(resp.Body)
+
+	reader, err := decompressBody(resp.Header.Get("Content-Encoding"), resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("decompressing response body: %w", err)
+	}
+	if reader != resp.Body {
+		defer func(Body io.ReadCloser) {
+			_ = Body.Close()
+		}(reader)
+	}
+
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("reading response body: %w", err)
+	}
+
+	if a.debug {
+		fmt.Printf("Response page %d ===================================== >>>\n", page)
+		fmt.Printf("%s\n", string(body))
+		fmt.Println("Response ===================================== <<<")
+	}
+
+	var apiResponse ApiResponse
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	if err = dec.Decode(&apiResponse); err != nil {
+		return nil, fmt.Errorf("parsing JSON: %w", err)
+	}
+
+	a.logger.Info("response received",
+		"url", pageURL,
+		"method", method,
+		"status", resp.StatusCode,
+		"duration_ms", time.Since(start).Milliseconds(),
+		"page", page,
+		"records", len(apiResponse.Data),
+	)
+
+	return &apiResponse, nil
+}
+
+// doRequestWithRetry sends a request built fresh by buildReq for each attempt (a request body
+// can only be read once), retrying on 5xx and 429 responses with exponential backoff. A
+// Retry-After header on the response, when present, overrides the computed backoff.
+func (a *Api) doRequestWithRetry(buildReq func() (*http.Request, error)) (*http.Response, error) {
+	backoff := 500 * time.Millisecond
+	var lastErr error
+
+	for attempt := 0; attempt <= maxRetries; attempt++ {
+		req, err := buildReq()
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := a.client.Do(req)
+		if err != nil {
+			lastErr = err
+		} else if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+			wait := backoff
+			if ra := resp.Header.Get("Retry-After"); ra != "" {
+				if secs, pErr := strconv.Atoi(ra); pErr == nil {
+					wait = time.Duration(secs) * time.Second
+				}
+			}
+			_ = resp.Body.Close()
+			lastErr = fmt.Errorf("status %d", resp.StatusCode)
+			if attempt == maxRetries {
+				break
+			}
+			a.logger.Warn("retrying request", "status", resp.StatusCode, "attempt", attempt+1, "wait_ms", wait.Milliseconds())
+			time.Sleep(wait)
+			backoff *= 2
+			continue
+		} else {
+			return resp, nil
+		}
+
+		if attempt == maxRetries {
+			break
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+
+	return nil, lastErr
+}
+
+// withPageParam returns rawURL with its "page" query parameter set to page.
+func withPageParam(rawURL string, page int) (string, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	params := parsed.Query()
+	params.Set("page", fmt.Sprintf("%d", page))
+	parsed.RawQuery = params.Encode()
+	return parsed.String(), nil
+}
@@ -0,0 +1,356 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	ansiGreen = "\033[32m"
+	ansiRed   = "\033[31m"
+	ansiReset = "\033[0m"
+)
+
+// Playbook describes an ordered list of HTTP steps to run against the configured API,
+// with per-step assertions and variables captured from the response for use in later steps.
+type Playbook struct {
+	Name  string          `yaml:"name"`
+	Steps []*PlaybookStep `yaml:"steps"`
+}
+
+// PlaybookStep is a single request in a Playbook.
+type PlaybookStep struct {
+	Name         string            `yaml:"name"`
+	Method       string            `yaml:"method"`
+	URL          string            `yaml:"url"`
+	Headers      map[string]string `yaml:"headers"`
+	Body         string            `yaml:"body"`
+	BodyFile     string            `yaml:"body_file"`
+	Multipart    string            `yaml:"multipart"`
+	ExpectStatus int               `yaml:"expect_status"`
+	Assertions   []string          `yaml:"assertions"`
+	Capture      map[string]string `yaml:"capture"`
+}
+
+// playbookVars holds the values captured by earlier steps, available to step templates (URL,
+// headers, body) as `{{ .steps.<name>.<field> }}`. Assertions are checked against the response only.
+type playbookVars struct {
+	Steps map[string]map[string]interface{}
+}
+
+// RunPlaybook loads the YAML playbook at path and executes its steps in order against the API.
+// It returns an error when the playbook cannot be loaded, and a non-nil error when any step fails,
+// so callers can translate that into a non-zero process exit code for CI use.
+func (a *Api) RunPlaybook(path string) error {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading playbook: %w", err)
+	}
+
+	var pb Playbook
+	if err = yaml.Unmarshal(raw, &pb); err != nil {
+		return fmt.Errorf("parsing playbook: %w", err)
+	}
+
+	fmt.Printf("Running playbook %q (%d steps)\n", pb.Name, len(pb.Steps))
+
+	vars := &playbookVars{Steps: make(map[string]map[string]interface{})}
+
+	failed := 0
+	for _, step := range pb.Steps {
+		if err = a.runPlaybookStep(step, vars); err != nil {
+			failed++
+			a.logger.Error("assertion failed", "step", step.Name, "error", err)
+			fmt.Printf("%s[FAIL]%s %s: %s\n", ansiRed, ansiReset, step.Name, err)
+			continue
+		}
+		fmt.Printf("%s[PASS]%s %s\n", ansiGreen, ansiReset, step.Name)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d steps failed", failed, len(pb.Steps))
+	}
+	return nil
+}
+
+func (a *Api) runPlaybookStep(step *PlaybookStep, vars *playbookVars) error {
+	url, err := renderTemplate(step.URL, vars)
+	if err != nil {
+		return fmt.Errorf("rendering url: %w", err)
+	}
+	url = fmt.Sprintf("%s%s", a.url, url)
+
+	body, contentType, err := step.resolveBody(vars, a.logger)
+	if err != nil {
+		return fmt.Errorf("resolving body: %w", err)
+	}
+
+	method := strings.ToUpper(step.Method)
+	if method == "" {
+		method = "GET"
+	}
+
+	requestBody, contentEncoding, err := a.compressRequestBody(body)
+	if err != nil {
+		return fmt.Errorf("compressing request body: %w", err)
+	}
+
+	req, err := http.NewRequest(method, url, bytes.NewReader(requestBody))
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Accept-Encoding", "gzip, deflate")
+	if contentEncoding != "" {
+		req.Header.Set("Content-Encoding", contentEncoding)
+	}
+	if a.token != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", a.token))
+	}
+	for k, v := range step.Headers {
+		rendered, rErr := renderTemplate(v, vars)
+		if rErr != nil {
+			return fmt.Errorf("rendering header %s: %w", k, rErr)
+		}
+		req.Header.Set(k, rendered)
+	}
+
+	if a.debug {
+		fmt.Printf("Request %s %s ===================================== >>>\n", method, url)
+		if len(body) > 0 {
+			var indented bytes.Buffer
+			if json.Indent(&indented, body, "", "  ") == nil {
+				fmt.Println(indented.String())
+			} else {
+				fmt.Println(string(body))
+			}
+		}
+		fmt.Println("Request ===================================== <<<")
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("making request: %w", err)
+	}
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(resp.Body)
+
+	reader, err := decompressBody(resp.Header.Get("Content-Encoding"), resp.Body)
+	if err != nil {
+		return fmt.Errorf("decompressing response body: %w", err)
+	}
+	if reader != resp.Body {
+		defer func(Body io.ReadCloser) {
+			_ = Body.Close()
+		}(reader)
+	}
+
+	respBody, err := io.ReadAll(reader)
+	if err != nil {
+		return fmt.Errorf("reading response body: %w", err)
+	}
+
+	if a.debug {
+		fmt.Println("Response ===================================== >>>")
+		var indented bytes.Buffer
+		if json.Indent(&indented, respBody, "", "  ") == nil {
+			fmt.Println(indented.String())
+		} else {
+			fmt.Println(string(respBody))
+		}
+		fmt.Println("Response ===================================== <<<")
+	}
+
+	if step.ExpectStatus != 0 && resp.StatusCode != step.ExpectStatus {
+		return fmt.Errorf("expected status %d, got %d", step.ExpectStatus, resp.StatusCode)
+	}
+
+	var parsed map[string]interface{}
+	if len(respBody) > 0 {
+		parsed, err = DecodeJSON(respBody)
+		if err != nil {
+			return fmt.Errorf("parsing response JSON: %w", err)
+		}
+	}
+
+	for _, assertion := range step.Assertions {
+		if err = checkAssertion(assertion, parsed); err != nil {
+			return err
+		}
+	}
+
+	if len(step.Capture) > 0 {
+		captured := make(map[string]interface{}, len(step.Capture))
+		for name, path := range step.Capture {
+			value, ok := lookupPath(parsed, path)
+			if !ok {
+				return fmt.Errorf("capture %s: path %q not found in response", name, path)
+			}
+			captured[name] = value
+		}
+		vars.Steps[step.Name] = captured
+	}
+
+	return nil
+}
+
+// resolveBody builds the request body for the step from whichever of Multipart, BodyFile, or
+// Body is set, and returns the Content-Type that goes with it.
+func (s *PlaybookStep) resolveBody(vars *playbookVars, logger *slog.Logger) ([]byte, string, error) {
+	if s.Multipart != "" {
+		body, contentType, err := buildMultipartBody(s.Multipart)
+		if err != nil {
+			return nil, "", err
+		}
+		return body, contentType, nil
+	}
+	if s.BodyFile != "" {
+		content, err := readFileContent("", s.BodyFile, logger)
+		if err != nil {
+			return nil, "", err
+		}
+		body, err := getJsonBytes(content)
+		if err != nil {
+			return nil, "", err
+		}
+		return body, "application/json", nil
+	}
+	if s.Body == "" {
+		return nil, "application/json", nil
+	}
+	rendered, err := renderTemplate(s.Body, vars)
+	if err != nil {
+		return nil, "", err
+	}
+	return []byte(rendered), "application/json", nil
+}
+
+// buildMultipartBody reads the file at path and wraps it in a multipart form, the same way
+// doMultipartPost does for the single-call mode.
+func buildMultipartBody(path string) ([]byte, string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, "", fmt.Errorf("opening file: %w", err)
+	}
+	defer func(file *os.File) {
+		_ = file.Close()
+	}(file)
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile("file", filepath.Base(path))
+	if err != nil {
+		return nil, "", fmt.Errorf("creating form file: %w", err)
+	}
+
+	if _, err = io.Copy(part, file); err != nil {
+		return nil, "", fmt.Errorf("copying file to form file: %w", err)
+	}
+
+	if err = writer.Close(); err != nil {
+		return nil, "", fmt.Errorf("closing writer: %w", err)
+	}
+
+	return body.Bytes(), writer.FormDataContentType(), nil
+}
+
+// renderTemplate executes text as a template against vars. The data is passed as a plain map
+// rather than the playbookVars struct directly, because text/template's field access is
+// case-sensitive and the documented capture syntax (`{{ .steps.login.token }}`) is lowercase.
+func renderTemplate(text string, vars *playbookVars) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+	tmpl, err := template.New("step").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	data := map[string]interface{}{"steps": vars.Steps}
+	var buf bytes.Buffer
+	if err = tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+var assertionPattern = regexp.MustCompile(`^\s*(.+?)\s*==\s*(.+?)\s*$`)
+
+// checkAssertion evaluates a single `path == value` expression against the decoded response.
+func checkAssertion(assertion string, response map[string]interface{}) error {
+	matches := assertionPattern.FindStringSubmatch(assertion)
+	if matches == nil {
+		return fmt.Errorf("assertion %q: expected form \"path == value\"", assertion)
+	}
+
+	actual, ok := lookupPath(response, matches[1])
+	if !ok {
+		return fmt.Errorf("assertion %q failed: path %q not found", assertion, matches[1])
+	}
+
+	expected, err := parseAssertionLiteral(matches[2])
+	if err != nil {
+		return fmt.Errorf("assertion %q: %w", assertion, err)
+	}
+
+	if fmt.Sprintf("%v", actual) != fmt.Sprintf("%v", expected) {
+		return fmt.Errorf("assertion %q failed: got %v", assertion, actual)
+	}
+	return nil
+}
+
+func parseAssertionLiteral(literal string) (interface{}, error) {
+	literal = strings.Trim(literal, `"`)
+	if literal == "true" || literal == "false" {
+		return strconv.ParseBool(literal)
+	}
+	if i, err := strconv.ParseInt(literal, 10, 64); err == nil {
+		return i, nil
+	}
+	if f, err := strconv.ParseFloat(literal, 64); err == nil {
+		return f, nil
+	}
+	return literal, nil
+}
+
+var pathSegmentPattern = regexp.MustCompile(`([^.\[\]]+)|\[(\d+)\]`)
+
+// lookupPath resolves a dotted, JSONPath-like expression such as "data[0].id" against a decoded
+// JSON object.
+func lookupPath(root map[string]interface{}, path string) (interface{}, bool) {
+	var current interface{} = root
+	for _, m := range pathSegmentPattern.FindAllStringSubmatch(path, -1) {
+		switch {
+		case m[1] != "":
+			asMap, ok := current.(map[string]interface{})
+			if !ok {
+				return nil, false
+			}
+			current, ok = asMap[m[1]]
+			if !ok {
+				return nil, false
+			}
+		case m[2] != "":
+			idx, _ := strconv.Atoi(m[2])
+			asSlice, ok := current.([]interface{})
+			if !ok || idx < 0 || idx >= len(asSlice) {
+				return nil, false
+			}
+			current = asSlice[idx]
+		}
+	}
+	return current, true
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Recognised --format values. FormatParquet is recognised, but NewOutputWriter returns an error
+// for it: this build has no parquet encoder.
+const (
+	FormatCSVWindows1251 = "csv-win1251"
+	FormatCSVUTF8        = "csv-utf8"
+	FormatJSON           = "json"
+	FormatNDJSON         = "ndjson"
+	FormatStdout         = "stdout"
+	FormatParquet        = "parquet"
+)
+
+// OutputWriter receives the column header once, then a stream of rows, and is closed when the
+// caller is done writing. Implementations decide how (and whether) a row is buffered.
+type OutputWriter interface {
+	WriteHeader(cols []string) error
+	WriteRow(row map[string]interface{}) error
+	Close() error
+}
+
+// NewOutputWriter opens dest and returns the OutputWriter for the given format. An empty format
+// defaults to the historical csv-win1251 behaviour. consoleOut is where FormatStdout writes; main
+// passes os.Stdout, so those rows share the console with the tool's own progress output.
+func NewOutputWriter(format, dest string, debug bool, consoleOut io.Writer, logger *slog.Logger) (OutputWriter, error) {
+	switch format {
+	case "", FormatCSVWindows1251:
+		return newCSVWriter(dest, true, debug, logger)
+	case FormatCSVUTF8:
+		return newCSVWriter(dest, false, debug, logger)
+	case FormatJSON:
+		file, err := os.Create(dest)
+		if err != nil {
+			return nil, fmt.Errorf("creating file: %w", err)
+		}
+		return &jsonWriter{file: file}, nil
+	case FormatNDJSON:
+		file, err := os.Create(dest)
+		if err != nil {
+			return nil, fmt.Errorf("creating file: %w", err)
+		}
+		return &ndjsonWriter{file: file, enc: json.NewEncoder(file)}, nil
+	case FormatStdout:
+		return &stdoutWriter{out: consoleOut}, nil
+	case FormatParquet:
+		return nil, fmt.Errorf("parquet output is not available in this build: no parquet encoder is vendored yet")
+	default:
+		return nil, fmt.Errorf("unknown output format: %s", format)
+	}
+}
+
+// resolveColumns returns the fixed column order: the --columns flag when given, otherwise the
+// sorted keys of the first row of data. Sorting keeps the header deterministic, unlike ranging
+// over a map directly. Rows are streamed page by page as they're fetched, so the header can only
+// be derived from the first page; if it comes back empty, fall back to --columns or give up.
+func resolveColumns(explicit []string, data []map[string]interface{}) []string {
+	if len(explicit) > 0 {
+		return explicit
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(data[0]))
+	for k := range data[0] {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// outputDestination maps the logical output name (e.g. "output.csv") to a path carrying the
+// right extension for format.
+func outputDestination(outputPath, output, format string) string {
+	name := strings.TrimSuffix(output, ".csv")
+	ext := ".csv"
+	switch format {
+	case FormatJSON:
+		ext = ".json"
+	case FormatNDJSON:
+		ext = ".ndjson"
+	case FormatParquet:
+		ext = ".parquet"
+	}
+	return fmt.Sprintf("%s%s%s", outputPath, name, ext)
+}
+
+// csvWriter writes rows as CSV, optionally transliterating values to Windows-1251 with
+// ConvertToWindows1251.
+type csvWriter struct {
+	file    *os.File
+	writer  *csv.Writer
+	header  []string
+	win1251 bool
+	debug   bool
+	logger  *slog.Logger
+}
+
+func newCSVWriter(dest string, win1251, debug bool, logger *slog.Logger) (*csvWriter, error) {
+	file, err := os.Create(dest)
+	if err != nil {
+		return nil, fmt.Errorf("creating file: %w", err)
+	}
+	return &csvWriter{file: file, writer: csv.NewWriter(file), win1251: win1251, debug: debug, logger: logger}, nil
+}
+
+func (w *csvWriter) WriteHeader(cols []string) error {
+	w.header = cols
+	return w.writer.Write(cols)
+}
+
+func (w *csvWriter) WriteRow(row map[string]interface{}) error {
+	record := make([]string, 0, len(w.header))
+	for _, key := range w.header {
+		value := fmt.Sprintf("%v", row[key])
+		value = strings.ReplaceAll(value, "\n", " ")
+		value = strings.ReplaceAll(value, "\r", "")
+		if w.win1251 {
+			encoded, e := ConvertToWindows1251(value)
+			if e != nil {
+				w.logger.Error("converting string to windows-1251", "value", value, "error", e)
+			}
+			value = encoded
+		}
+		record = append(record, value)
+	}
+	return w.writer.Write(record)
+}
+
+func (w *csvWriter) Close() error {
+	w.writer.Flush()
+	if err := w.writer.Error(); err != nil {
+		return err
+	}
+	return w.file.Close()
+}
+
+// jsonWriter buffers every row and emits a single indented JSON array on Close.
+type jsonWriter struct {
+	file *os.File
+	rows []map[string]interface{}
+}
+
+func (w *jsonWriter) WriteHeader(_ []string) error { return nil }
+
+func (w *jsonWriter) WriteRow(row map[string]interface{}) error {
+	w.rows = append(w.rows, row)
+	return nil
+}
+
+func (w *jsonWriter) Close() error {
+	enc := json.NewEncoder(w.file)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(w.rows); err != nil {
+		_ = w.file.Close()
+		return err
+	}
+	return w.file.Close()
+}
+
+// ndjsonWriter streams one JSON object per line as rows arrive, so a gigabyte response never
+// needs to be buffered in memory.
+type ndjsonWriter struct {
+	file *os.File
+	enc  *json.Encoder
+}
+
+func (w *ndjsonWriter) WriteHeader(_ []string) error { return nil }
+
+func (w *ndjsonWriter) WriteRow(row map[string]interface{}) error {
+	return w.enc.Encode(row)
+}
+
+func (w *ndjsonWriter) Close() error {
+	return w.file.Close()
+}
+
+// stdoutWriter prints each row as key/value lines to out, for ad-hoc inspection without
+// opening a file.
+type stdoutWriter struct {
+	header []string
+	out    io.Writer
+}
+
+func (w *stdoutWriter) WriteHeader(cols []string) error {
+	w.header = cols
+	return nil
+}
+
+func (w *stdoutWriter) WriteRow(row map[string]interface{}) error {
+	for _, key := range w.header {
+		fmt.Fprintf(w.out, "%s: %v\n", key, row[key])
+	}
+	fmt.Fprintln(w.out)
+	return nil
+}
+
+func (w *stdoutWriter) Close() error { return nil }